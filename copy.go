@@ -0,0 +1,75 @@
+package fs
+
+import (
+	"errors"
+	"io"
+)
+
+// errCopyUnsupported is returned internally by the platform-specific
+// zero-copy helpers when the source/destination combination (or the
+// kernel) doesn't support the attempted fast path, so the caller should
+// fall back to a generic buffered copy.
+var errCopyUnsupported = errors.New("fs: zero-copy unsupported for this fd pair")
+
+// ReadFrom implements io.ReaderFrom. When r is also an IO returned by
+// (*File).IO, ReadFrom first attempts a zero-copy transfer directly in the
+// kernel (copy_file_range or sendfile on Linux, sendfile on Darwin) before
+// falling back to a generic buffered copy. Bytes already moved by a fast
+// path that later hits an unsupported combination are preserved; the
+// buffered fallback picks up from the files' current offsets.
+func (self fio) ReadFrom(r io.Reader) (int64, error) {
+	src, ok := r.(fio)
+	if !ok {
+		return genericCopy(self, r)
+	}
+
+	n, err := copyFile(self.ctx, self.f, src.f)
+	if err != errCopyUnsupported {
+		return n, err
+	}
+	more, err := genericCopy(self, src)
+	return n + more, err
+}
+
+// WriteTo implements io.WriterTo, with the same zero-copy fast path as
+// ReadFrom.
+func (self fio) WriteTo(w io.Writer) (int64, error) {
+	dst, ok := w.(fio)
+	if !ok {
+		return genericCopy(w, self)
+	}
+
+	n, err := copyFile(self.ctx, dst.f, self.f)
+	if err != errCopyUnsupported {
+		return n, err
+	}
+	more, err := genericCopy(dst, self)
+	return n + more, err
+}
+
+// genericCopy is io.Copy's buffered loop, reimplemented so that it can be
+// called with a dst/src that is itself an io.ReaderFrom/io.WriterTo (fio)
+// without io.Copy recursing back into ReadFrom/WriteTo.
+func genericCopy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := make([]byte, 32*1024)
+	var written int64
+	for {
+		nr, er := src.Read(buf)
+		if nr > 0 {
+			nw, ew := dst.Write(buf[:nr])
+			written += int64(nw)
+			if ew != nil {
+				return written, ew
+			}
+			if nr != nw {
+				return written, io.ErrShortWrite
+			}
+		}
+		if er != nil {
+			if er != io.EOF {
+				return written, er
+			}
+			return written, nil
+		}
+	}
+}
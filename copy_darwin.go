@@ -0,0 +1,59 @@
+// +build darwin
+
+package fs
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/net/context"
+)
+
+// copyFile has a fast path only when exactly one side of the pair is a
+// pipe, using sendfile. A pair of regular files has no fast path on
+// Darwin: that would need fcopyfile(3), which lives in libc rather than
+// being a raw syscall, and this package avoids cgo (it needs its own
+// sigtramp/threadID assembly to work without it). Regular-to-regular
+// pairs always fall back to the generic buffered copy in copy.go.
+func copyFile(ctx context.Context, dst, src *File) (int64, error) {
+	if dst.pollable == src.pollable {
+		return 0, errCopyUnsupported
+	}
+	return sendFile(ctx, dst, src)
+}
+
+func sendFile(ctx context.Context, dst, src *File) (int64, error) {
+	defer interrupt(ctx)()
+
+	var total int64
+	for {
+		n, err := syscall.Sendfile(int(dst.f.Fd()), int(src.f.Fd()), nil, 1<<30)
+		if err != nil {
+			if err == syscall.EINTR {
+				select {
+				case <-ctx.Done():
+					return total, copyPathErr(dst, context.Canceled)
+				default:
+					continue
+				}
+			}
+			if total == 0 && (err == syscall.EINVAL || err == syscall.ENOSYS) {
+				return 0, errCopyUnsupported
+			}
+			return total, copyPathErr(dst, err)
+		}
+		if n == 0 {
+			return total, nil
+		}
+		total += int64(n)
+		select {
+		case <-ctx.Done():
+			return total, copyPathErr(dst, context.Canceled)
+		default:
+		}
+	}
+}
+
+func copyPathErr(dst *File, err error) error {
+	return &os.PathError{Op: "copy", Path: dst.Name(), Err: err}
+}
@@ -0,0 +1,116 @@
+package fs
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/net/context"
+)
+
+// sysCopyFileRange is the copy_file_range(2) syscall number for the
+// running architecture, or 0 if this package doesn't know it (in which
+// case copyFileRange always reports errCopyUnsupported).
+var sysCopyFileRange uintptr
+
+func init() {
+	switch runtime.GOARCH {
+	case "amd64":
+		sysCopyFileRange = 326
+	case "386":
+		sysCopyFileRange = 377
+	case "arm64":
+		sysCopyFileRange = 285
+	}
+}
+
+// copyFile attempts an in-kernel transfer between two regular files with
+// copy_file_range, or between a pipe and a regular file with sendfile. Any
+// other combination (two pipes, a socket, etc.) returns errCopyUnsupported
+// immediately so the caller falls back to a buffered copy.
+func copyFile(ctx context.Context, dst, src *File) (int64, error) {
+	switch {
+	case !dst.pollable && !src.pollable:
+		n, err := copyFileRange(ctx, dst, src)
+		if err != errCopyUnsupported {
+			return n, err
+		}
+		return sendFile(ctx, dst, src)
+	case dst.pollable != src.pollable:
+		return sendFile(ctx, dst, src)
+	default:
+		return 0, errCopyUnsupported
+	}
+}
+
+func copyFileRange(ctx context.Context, dst, src *File) (int64, error) {
+	if sysCopyFileRange == 0 {
+		return 0, errCopyUnsupported
+	}
+	defer interrupt(ctx)()
+
+	var total int64
+	for {
+		n, _, errno := syscall.Syscall6(sysCopyFileRange,
+			src.f.Fd(), 0, dst.f.Fd(), 0, 1<<30, 0)
+		if errno != 0 {
+			if errno == syscall.EINTR {
+				select {
+				case <-ctx.Done():
+					return total, copyPathErr(dst, context.Canceled)
+				default:
+					continue
+				}
+			}
+			if total == 0 && (errno == syscall.EINVAL || errno == syscall.ENOSYS || errno == syscall.EXDEV) {
+				return 0, errCopyUnsupported
+			}
+			return total, copyPathErr(dst, errno)
+		}
+		if n == 0 {
+			return total, nil
+		}
+		total += int64(n)
+		select {
+		case <-ctx.Done():
+			return total, copyPathErr(dst, context.Canceled)
+		default:
+		}
+	}
+}
+
+func sendFile(ctx context.Context, dst, src *File) (int64, error) {
+	defer interrupt(ctx)()
+
+	var total int64
+	for {
+		n, err := syscall.Sendfile(int(dst.f.Fd()), int(src.f.Fd()), nil, 1<<30)
+		if err != nil {
+			if err == syscall.EINTR {
+				select {
+				case <-ctx.Done():
+					return total, copyPathErr(dst, context.Canceled)
+				default:
+					continue
+				}
+			}
+			if total == 0 && (err == syscall.EINVAL || err == syscall.ENOSYS) {
+				return 0, errCopyUnsupported
+			}
+			return total, copyPathErr(dst, err)
+		}
+		if n == 0 {
+			return total, nil
+		}
+		total += int64(n)
+		select {
+		case <-ctx.Done():
+			return total, copyPathErr(dst, context.Canceled)
+		default:
+		}
+	}
+}
+
+func copyPathErr(dst *File, err error) error {
+	return &os.PathError{Op: "copy", Path: dst.Name(), Err: err}
+}
@@ -0,0 +1,12 @@
+// +build windows
+
+package fs
+
+import "golang.org/x/net/context"
+
+// copyFile has no Windows fast path yet (CopyFileEx et al would need their
+// own cancellation story); ReadFrom/WriteTo always fall back to the
+// generic buffered copy here.
+func copyFile(ctx context.Context, dst, src *File) (int64, error) {
+	return 0, errCopyUnsupported
+}
@@ -0,0 +1,138 @@
+package fs
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// deadlineState tracks an optional read or write deadline for a File,
+// modeled on the Set{Read,Write}Deadline pair on net.Conn. Arming a
+// deadline starts a timer that closes the channel returned by expired
+// when it fires. Any goroutine already selecting on that channel — the
+// poller's wait loop, or the interrupt goroutine watching a blocked
+// regular-file syscall — wakes immediately, regardless of how long it has
+// been waiting.
+type deadlineState struct {
+	mu    sync.Mutex
+	t     time.Time
+	timer *time.Timer
+	ch    chan struct{}
+}
+
+// set arms the deadline at t, or disarms it if t is the zero Time.
+func (d *deadlineState) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prevArmed := !d.t.IsZero()
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.t = t
+
+	switch {
+	case d.ch == nil:
+		d.ch = make(chan struct{})
+	case prevArmed:
+		// The deadline being replaced may already have a fire callback
+		// queued on another goroutine: Timer.Stop returning false doesn't
+		// mean that callback won't still run. Always hand out a fresh
+		// channel here so a stale fire can only close the channel it was
+		// armed against, never the one this new deadline is about to use.
+		d.ch = make(chan struct{})
+	default:
+		select {
+		case <-d.ch:
+			d.ch = make(chan struct{})
+		default:
+			// Never armed and not closed: keep it, so a caller already
+			// watching via expired (from before any deadline was ever
+			// set on this File) observes this arm.
+		}
+	}
+
+	if t.IsZero() {
+		return
+	}
+	dur := time.Until(t)
+	if dur <= 0 {
+		close(d.ch)
+		return
+	}
+	ch := d.ch
+	d.timer = time.AfterFunc(dur, func() { d.fire(ch) })
+}
+
+func (d *deadlineState) fire(ch chan struct{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+}
+
+// expired returns the channel that closes once the current deadline
+// passes. It never closes on its own if no deadline is set.
+func (d *deadlineState) expired() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ch == nil {
+		d.ch = make(chan struct{})
+	}
+	return d.ch
+}
+
+// deadlineCtx returns a derived context that is also done once d expires,
+// so that the signal-based interrupt path (which only ever watches
+// ctx.Done) wakes a syscall blocked on a non-pollable descriptor without
+// needing its own plumbing. It merges in d's expiry unconditionally, even
+// if no deadline is armed yet: net.Conn's contract is that SetDeadline
+// called on another goroutine affects a Read or Write already in
+// progress, not just ones started afterward, and expired's channel is
+// reused across an unarmed-to-armed transition precisely so a late arm is
+// observed here. The returned cleanup must be called once the operation
+// is done, whether or not the deadline fired.
+func deadlineCtx(ctx context.Context, d *deadlineState) (context.Context, func()) {
+	expired := d.expired()
+
+	select {
+	case <-expired:
+		dctx, cancel := context.WithCancel(ctx)
+		cancel()
+		return dctx, func() {}
+	default:
+	}
+
+	dctx, cancel := context.WithCancel(ctx)
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-expired:
+			cancel()
+		case <-stop:
+		}
+	}()
+	return dctx, func() { close(stop); cancel() }
+}
+
+// deadlineErr rewrites err's *os.PathError.Err from context.Canceled to
+// os.ErrDeadlineExceeded if d's deadline is why the operation stopped,
+// distinguishing it from an explicit ctx cancellation.
+func deadlineErr(d *deadlineState, err error) error {
+	perr, ok := err.(*os.PathError)
+	if !ok || perr.Err != context.Canceled {
+		return err
+	}
+	select {
+	case <-d.expired():
+		perr.Err = os.ErrDeadlineExceeded
+	default:
+	}
+	return err
+}
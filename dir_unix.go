@@ -0,0 +1,106 @@
+// +build !windows
+
+package fs
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/net/context"
+)
+
+// readdirBatch bounds how many entries are requested from the kernel per
+// getdents call inside Readdir/Readdirnames, so that a walk of a very
+// large (or slow, e.g. NFS-mounted) directory stays interruptible between
+// batches instead of only at the start and the end.
+const readdirBatch = 128
+
+// Readdir reads the contents of the directory associated with f and
+// returns a slice of up to n FileInfo, in the order the directory is
+// read, as in (*os.File).Readdir. If n <= 0, Readdir reads all the
+// remaining entries.
+//
+// If there is an error, it will be of type *os.PathError.
+func (f *File) Readdir(ctx context.Context, n int) ([]os.FileInfo, error) {
+	var all []os.FileInfo
+	for {
+		select {
+		case <-ctx.Done():
+			return all, &os.PathError{Op: "readdir", Path: f.Name(), Err: context.Canceled}
+		default:
+		}
+
+		want := readdirBatch
+		if n > 0 {
+			if remaining := n - len(all); remaining < want {
+				want = remaining
+			}
+			if want <= 0 {
+				return all, nil
+			}
+		}
+
+		var fis []os.FileInfo
+		err := func() error {
+			defer interrupt(ctx)()
+			return retryEINTR(ctx, func() (err error) {
+				fis, err = f.f.Readdir(want)
+				return err
+			})
+		}()
+		all = append(all, fis...)
+		if err == io.EOF {
+			return all, nil
+		}
+		if err != nil {
+			return all, err
+		}
+		if len(fis) < want {
+			return all, nil // short batch: directory exhausted
+		}
+	}
+}
+
+// Readdirnames is like Readdir but returns only the names of the
+// directory entries.
+//
+// If there is an error, it will be of type *os.PathError.
+func (f *File) Readdirnames(ctx context.Context, n int) ([]string, error) {
+	var all []string
+	for {
+		select {
+		case <-ctx.Done():
+			return all, &os.PathError{Op: "readdirnames", Path: f.Name(), Err: context.Canceled}
+		default:
+		}
+
+		want := readdirBatch
+		if n > 0 {
+			if remaining := n - len(all); remaining < want {
+				want = remaining
+			}
+			if want <= 0 {
+				return all, nil
+			}
+		}
+
+		var names []string
+		err := func() error {
+			defer interrupt(ctx)()
+			return retryEINTR(ctx, func() (err error) {
+				names, err = f.f.Readdirnames(want)
+				return err
+			})
+		}()
+		all = append(all, names...)
+		if err == io.EOF {
+			return all, nil
+		}
+		if err != nil {
+			return all, err
+		}
+		if len(names) < want {
+			return all, nil // short batch: directory exhausted
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// +build windows
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/net/context"
+)
+
+// readdirBatch bounds how many entries Readdir/Readdirnames fetch between
+// ctx.Done checks.
+const readdirBatch = 128
+
+// Readdir reads the contents of the directory associated with f and
+// returns a slice of up to n FileInfo. If n <= 0, Readdir reads all the
+// remaining entries.
+func (f *File) Readdir(ctx context.Context, n int) ([]os.FileInfo, error) {
+	d, err := os.Open(f.name)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	var all []os.FileInfo
+	for n <= 0 || len(all) < n {
+		select {
+		case <-ctx.Done():
+			return all, &os.PathError{Op: "readdir", Path: f.name, Err: context.Canceled}
+		default:
+		}
+
+		want := readdirBatch
+		if n > 0 {
+			if remaining := n - len(all); remaining < want {
+				want = remaining
+			}
+		}
+		fis, err := d.Readdir(want)
+		all = append(all, fis...)
+		if len(fis) < want {
+			return all, nil
+		}
+		if err != nil {
+			return all, err
+		}
+	}
+	return all, nil
+}
+
+// Readdirnames is like Readdir but returns only the names of the
+// directory entries.
+func (f *File) Readdirnames(ctx context.Context, n int) ([]string, error) {
+	fis, err := f.Readdir(ctx, n)
+	names := make([]string, len(fis))
+	for i, fi := range fis {
+		names[i] = fi.Name()
+	}
+	return names, err
+}
@@ -2,6 +2,7 @@ package fs
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -44,7 +45,65 @@ func TestOpen(t *testing.T) {
 	}
 }
 
-// TODO: test spurious signal does not cancel
+// TestSpuriousSignalDuringRead fires SIGUSR1 at the blocked goroutine from
+// an unrelated goroutine, simulating some other library in the process
+// using the signal for its own purposes. Since ctx is never canceled, the
+// read should simply retry and complete normally rather than surfacing
+// the interruption as an error. It drives the read through fio.Read (the
+// non-pollable, signal-interrupt path exercised by regular files) rather
+// than the raw os.File, so it's errAgain's EINTR handling under test, not
+// the standard library's own.
+func TestSpuriousSignalDuringRead(t *testing.T) {
+	ctx := context.Background()
+
+	osr, osw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// pollable is forced false so Read goes through the signal-interrupt
+	// path even though a pipe would normally be handled by the poller.
+	r := &File{f: osr, fd: int(osr.Fd())}
+	w := newFile(osw)
+	defer r.f.Close()
+	// w is pollable and must be torn down through fio.Close, not the raw
+	// os.File, so pollDescForget runs and the poller doesn't hang on to a
+	// stale registration once the OS recycles this fd for a later test.
+	defer w.IO(ctx).Close()
+
+	type result struct {
+		n   int
+		err error
+	}
+	tid := make(chan uintptr, 1)
+	done := make(chan result, 1)
+	go func() {
+		tid <- threadID()
+		n, err := r.IO(ctx).Read(make([]byte, 1<<8))
+		done <- result{n, err}
+	}()
+
+	// Wait for the goroutine to report its OS thread, then give it a
+	// moment to actually enter the blocking read syscall.
+	sendTo := <-tid
+	time.Sleep(50 * time.Millisecond)
+
+	if err := threadKill(sendTo); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := w.IO(ctx).Write([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+
+	res := <-done
+	if res.err != nil {
+		t.Errorf("read after spurious signal: %v", res.err)
+	}
+	if res.n != 2 {
+		t.Errorf("read after spurious signal: got %d bytes, want 2", res.n)
+	}
+}
 
 // This test does not work on darwin. A write(2) that is partially started
 // will be restarted even if the handler does not specify SA_RESTART. Ugh.
@@ -78,8 +137,11 @@ func TestWriteInterruptPipe(t *testing.T) {
 	<-done
 	log.Printf("test done")
 
-	w.f.Close()
-	r.f.Close()
+	// Both ends are pollable; close them through fio.Close, not the raw
+	// os.File, so pollDescForget runs and the poller doesn't hang on to a
+	// stale registration once the OS recycles these fds for a later test.
+	w.IO(ctx).Close()
+	r.IO(ctx).Close()
 }
 
 var signalCaught bool
@@ -158,6 +220,160 @@ func TestCancelRead(t *testing.T) {
 		t.Errorf("not canceled, got: %v", readErr)
 	}
 
-	w.f.Close()
-	r.f.Close()
+	// Both ends are pollable; close them through fio.Close, not the raw
+	// os.File, so pollDescForget runs and the poller doesn't hang on to a
+	// stale registration once the OS recycles these fds for a later test.
+	w.IO(ctx).Close()
+	r.IO(ctx).Close()
+}
+
+func TestReadDeadline(t *testing.T) {
+	ctx := context.Background()
+
+	// Manually assemble the pipe to avoid setting it to non-blocking, so
+	// the read below goes through the poller path.
+	osr, osw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, w := newFile(osr), newFile(osw)
+	// Both ends are pollable; close them through fio.Close, not the raw
+	// os.File, so pollDescForget runs and the poller doesn't hang on to a
+	// stale registration once the OS recycles these fds for a later test.
+	defer w.IO(ctx).Close()
+	defer r.IO(ctx).Close()
+
+	r.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+
+	start := time.Now()
+	_, err = r.IO(ctx).Read(make([]byte, 1<<8))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Read blocked for %v past its deadline", elapsed)
+	}
+
+	perr, ok := err.(*os.PathError)
+	if !ok || perr.Err != os.ErrDeadlineExceeded {
+		t.Errorf("Read after deadline: got %v, want os.ErrDeadlineExceeded", err)
+	}
+
+	// Clearing the deadline lets a subsequent Read block normally again.
+	r.SetReadDeadline(time.Time{})
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.IO(ctx).Read(make([]byte, 1<<8))
+		done <- err
+	}()
+	if _, err := w.IO(ctx).Write([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("read after clearing deadline: %v", err)
+	}
+}
+
+// TestDeadlineCtxArmAfterStart covers the case SetReadDeadline is meant to
+// handle beyond a deadline that's already armed before a call starts:
+// matching net.Conn, setting it from another goroutine must also wake a
+// call (here, the derived ctx deadlineCtx hands to the signal-based
+// interrupt path on regular files) that began before any deadline existed.
+func TestDeadlineCtxArmAfterStart(t *testing.T) {
+	var d deadlineState
+	ctx, cancel := deadlineCtx(context.Background(), &d)
+	defer cancel()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		d.set(time.Now().Add(10 * time.Millisecond))
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("deadlineCtx's context never became done after a deadline was armed on an already-running call")
+	}
+}
+
+// TestDeadlineRearmNotClosedByStaleFire guards against a timer callback
+// for a deadline that has since been replaced closing the channel the
+// new deadline uses. Timer.Stop returning false doesn't mean the old
+// callback won't still run, so fire must only ever affect the specific
+// channel it was armed against.
+func TestDeadlineRearmNotClosedByStaleFire(t *testing.T) {
+	var d deadlineState
+	d.set(time.Now().Add(time.Hour))
+	d.mu.Lock()
+	stale := d.ch
+	d.mu.Unlock()
+
+	// A second deadline replaces the first before its timer fires...
+	d.set(time.Now().Add(time.Hour))
+
+	// ...but the first timer's callback was already queued and runs late.
+	d.fire(stale)
+
+	select {
+	case <-d.expired():
+		t.Fatal("re-armed deadline reported expired due to a stale fire from the deadline it replaced")
+	default:
+	}
+}
+
+func TestStatMkdirRemove(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "fs-stat-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sub := dir + "/sub"
+	if err := Mkdir(ctx, sub, 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	fi, err := Stat(ctx, sub)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if !fi.IsDir() {
+		t.Errorf("Stat(%q).IsDir() = false, want true", sub)
+	}
+
+	if err := Remove(ctx, sub); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := Stat(ctx, sub); !os.IsNotExist(err) {
+		t.Errorf("Stat after Remove: got %v, want a not-exist error", err)
+	}
+}
+
+func TestReaddir(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "fs-readdir-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const count = readdirBatch + 7 // force more than one batch
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("%s/f%03d", dir, i)
+		if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := Open(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := d.Readdirnames(ctx, -1)
+	if err != nil {
+		t.Fatalf("Readdirnames: %v", err)
+	}
+	if len(names) != count {
+		t.Errorf("Readdirnames returned %d names, want %d", len(names), count)
+	}
 }
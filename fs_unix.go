@@ -0,0 +1,417 @@
+// +build !windows
+
+// Unix implementation: Read and Write on pollable descriptors (pipes,
+// sockets, FIFOs, ttys) are cancelled by waking a goroutine parked in the
+// package poller; regular files, which have no portable readiness
+// notification, fall back to interrupting the blocking syscall with
+// SIGUSR1. See fs_windows.go for the IOCP-based implementation used there.
+package fs
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// File holds an open file descriptor.
+type File struct {
+	f *os.File
+
+	// fd is f.f.Fd(), cached once at creation. (*os.File).Fd() calls
+	// SetBlocking on every call for a descriptor opened in non-blocking
+	// mode (true for pipes and other pollable descriptors), silently
+	// clearing O_NONBLOCK each time it's invoked; calling it more than
+	// once per File would undo setnonblock's work out from under us.
+	fd int
+
+	// pollable is true for pipes, sockets, FIFOs, and ttys: descriptors
+	// whose Read/Write are cancelled via the package poller instead of
+	// signals.
+	pollable bool
+
+	pollOnce sync.Once
+	pd       *pollDesc
+
+	// released is set to 1 the first time this File is torn down, by
+	// whichever of an explicit Close or the finalizer gets there first.
+	// It guards both the OpenLimit slot release and pollDescForget, so a
+	// finalizer that fires after an explicit Close never calls
+	// pollDescForget again on an fd number the OS may have since recycled
+	// for an unrelated File.
+	released int32
+
+	readDeadline  deadlineState
+	writeDeadline deadlineState
+}
+
+// IO returns an IO object bound to ctx for all of its operations.
+//
+// The underlying file descriptor is shared with File. IO can be called
+// multiple times with different ctx values.
+func (f *File) IO(ctx context.Context) IO {
+	return fio{f, ctx}
+}
+
+// poll arms f for poller-based cancellation the first time it is called,
+// and returns the pollDesc tracking its waiters.
+func (f *File) poll() *pollDesc {
+	f.pollOnce.Do(func() {
+		setnonblock(uintptr(f.fd))
+		f.pd = pollDescFor(f.fd)
+	})
+	return f.pd
+}
+
+// Name returns the name of the file as presented to Open.
+func (f *File) Name() string {
+	return f.f.Name()
+}
+
+// SetNonBlocking puts the underlying file descriptor into non-blocking mode.
+// This is equivalent to O_NONBLOCK.
+func (f *File) SetNonBlocking() {
+	setnonblock(uintptr(f.fd))
+}
+
+// SetDeadline sets the read and write deadlines for f, as SetReadDeadline
+// and SetWriteDeadline.
+func (f *File) SetDeadline(t time.Time) error {
+	f.readDeadline.set(t)
+	f.writeDeadline.set(t)
+	return nil
+}
+
+// SetReadDeadline sets the deadline for future Read calls on f's IO, as
+// well as any Read call currently blocked. A zero value for t disables
+// the deadline. Once a deadline passes, Read returns an *os.PathError
+// wrapping os.ErrDeadlineExceeded, until SetReadDeadline is called again.
+func (f *File) SetReadDeadline(t time.Time) error {
+	f.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline is like SetReadDeadline but for Write.
+func (f *File) SetWriteDeadline(t time.Time) error {
+	f.writeDeadline.set(t)
+	return nil
+}
+
+func newFile(osf *os.File) *File {
+	if osf == nil {
+		return nil
+	}
+	fd := int(osf.Fd())
+	f := &File{
+		f:        osf,
+		fd:       fd,
+		pollable: isPollable(uintptr(fd)),
+	}
+	runtime.SetFinalizer(f, func(f *File) {
+		if atomic.CompareAndSwapInt32(&f.released, 0, 1) {
+			if f.pollable {
+				pollDescForget(f.fd)
+			}
+			releaseOpenSlot()
+		}
+		f.f.Close()
+	})
+	return f
+}
+
+// Open opens the named file for reading.
+//
+// If the number of opened files exceeds OpenLimit, Open will block until
+// another file is closed.
+//
+// If there is an error, it will be of type *PathError.
+func Open(ctx context.Context, name string) (file *File, err error) {
+	return OpenFile(ctx, name, os.O_RDONLY, 0)
+}
+
+// OpenFile is the generalized open call; most users will use Open
+// or Create instead.
+//
+// If the number of open files exceeds OpenLimit, OpenFile will block until
+// another file is closed or ctx is done.
+//
+// If there is an error, it will be of type *os.PathError.
+func OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (file *File, err error) {
+	if err := acquireOpen(ctx); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	defer interrupt(ctx)()
+	var f *os.File
+	err = retryEINTR(ctx, func() (err error) {
+		f, err = os.OpenFile(name, flag, perm)
+		return err
+	})
+	if err != nil {
+		releaseOpenSlot()
+		return nil, err
+	}
+	return newFile(f), nil
+}
+
+// Pipe returns a connected pair of Files; reads from r return bytes written to w.
+//
+// Each end of the pipe counts separately against OpenLimit.
+func Pipe(ctx context.Context) (r, w *File, err error) {
+	if err := acquireOpen(ctx); err != nil {
+		return nil, nil, err
+	}
+	if err := acquireOpen(ctx); err != nil {
+		releaseOpenSlot()
+		return nil, nil, err
+	}
+
+	var osr, osw *os.File
+	err = retryEINTR(ctx, func() (err error) {
+		osr, osw, err = os.Pipe()
+		return err
+	})
+	if err != nil {
+		releaseOpenSlot()
+		releaseOpenSlot()
+		return nil, nil, err
+	}
+	return newFile(osr), newFile(osw), nil
+}
+
+type fio struct {
+	f   *File
+	ctx context.Context
+}
+
+func (fio fio) Seek(offset int64, whence int) (ret int64, err error) {
+	defer interrupt(fio.ctx)()
+	err = retryEINTR(fio.ctx, func() (err error) {
+		ret, err = fio.f.f.Seek(offset, whence)
+		return err
+	})
+	return ret, err
+}
+
+// retryEINTR re-invokes op until it returns something other than EINTR, or
+// ctx is done, re-checking ctx.Done() between attempts so a spurious
+// signal sent to the process for some other purpose (not a cancellation)
+// doesn't surface as a failed syscall. op's error is expected to be either
+// *os.PathError (most callers) or *os.LinkError (Rename, Symlink); any
+// other error type is returned as-is.
+func retryEINTR(ctx context.Context, op func() error) error {
+	for {
+		err := op()
+		var errp *error
+		switch e := err.(type) {
+		case *os.PathError:
+			errp = &e.Err
+		case *os.LinkError:
+			errp = &e.Err
+		default:
+			return err
+		}
+		if *errp != syscall.EINTR {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			*errp = context.Canceled
+			return err
+		default:
+			// Spurious: keep going.
+		}
+	}
+}
+
+// errAgain checks for EAGAIN or EINTR.
+func errAgain(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	perr, ok := err.(*os.PathError)
+	if !ok {
+		return err
+	}
+	switch perr.Err {
+	case syscall.EAGAIN:
+		return nil
+	case syscall.EINTR:
+		// Double check that the context is canceled.
+		// If not, this may be a spurious signal
+		// sent to the program for some other purpose.
+		select {
+		case <-ctx.Done():
+			perr.Err = context.Canceled
+			return perr
+		default:
+			return nil // keep going
+		}
+	default:
+		return err
+	}
+}
+
+func (fio fio) Write(p []byte) (int, error) {
+	if fio.f.pollable {
+		return fio.pollWrite(p)
+	}
+
+	ctx, cancel := deadlineCtx(fio.ctx, &fio.f.writeDeadline)
+	defer cancel()
+	defer interrupt(ctx)()
+	n := 0
+	for len(p) > 0 {
+		wn, err := fio.f.f.Write(p)
+		n += wn
+		p = p[wn:]
+		err = errAgain(ctx, err)
+		if err != nil {
+			return n, deadlineErr(&fio.f.writeDeadline, err)
+		}
+		select {
+		case <-ctx.Done():
+			return n, deadlineErr(&fio.f.writeDeadline, &os.PathError{
+				Op:   "write",
+				Path: fio.f.Name(),
+				Err:  context.Canceled,
+			})
+		default:
+		}
+	}
+	return n, nil
+}
+
+// pollWrite is the Write path for pollable descriptors: pipes, sockets,
+// FIFOs, and ttys. It issues the write with the raw syscall rather than
+// through (*os.File).Write: the os package's own FD wraps every pollable
+// descriptor in the Go runtime's internal netpoller, and an EAGAIN from it
+// is retried there instead of being returned to us, which would leave our
+// poller and cancellation path never in the loop at all. It never sends a
+// signal; a canceled ctx, or an expired write deadline, simply wakes the
+// goroutine parked in the poller.
+func (fio fio) pollWrite(p []byte) (int, error) {
+	pd := fio.f.poll()
+	fd := fio.f.fd
+	n := 0
+	for len(p) > 0 {
+		// Register with the poller before retrying the syscall below; see
+		// the comment on (*pollDesc).register for why the order matters.
+		ready := pd.register(true)
+		wn, err := syscall.Write(fd, p)
+		if wn > 0 {
+			n += wn
+			p = p[wn:]
+		}
+		if err == nil {
+			pd.remove(true, ready)
+			continue
+		}
+		if err == syscall.EINTR {
+			pd.remove(true, ready)
+			continue
+		}
+		if err != syscall.EAGAIN {
+			pd.remove(true, ready)
+			return n, pollPathErr("write", fio.f.f, err)
+		}
+		if werr := pd.wait(fio.ctx, true, ready, fio.f.writeDeadline.expired()); werr != nil {
+			return n, pollPathErr("write", fio.f.f, werr)
+		}
+	}
+	return n, nil
+}
+
+func (fio fio) Read(data []byte) (int, error) {
+	if fio.f.pollable {
+		return fio.pollRead(data)
+	}
+
+	ctx, cancel := deadlineCtx(fio.ctx, &fio.f.readDeadline)
+	defer cancel()
+	defer interrupt(ctx)()
+
+	// The io.Reader contract encourages us not to return zero bytes,
+	// so we spin on EAGAIN until we are canceled or bytes appear.
+	for {
+		n, err := fio.f.f.Read(data)
+		err = errAgain(ctx, err)
+		if err != nil {
+			return n, deadlineErr(&fio.f.readDeadline, err)
+		}
+		if n > 0 {
+			return n, err
+		}
+		select {
+		case <-ctx.Done():
+			return n, deadlineErr(&fio.f.readDeadline, &os.PathError{
+				Op:   "read",
+				Path: fio.f.Name(),
+				Err:  context.Canceled,
+			})
+		default:
+		}
+	}
+	return len(data), nil
+}
+
+// pollRead is the Read path for pollable descriptors: pipes, sockets,
+// FIFOs, and ttys. Like pollWrite, it reads with the raw syscall so that
+// an EAGAIN reaches our own poller instead of the Go runtime's. It never
+// sends a signal; a canceled ctx, or an expired read deadline, simply
+// wakes the goroutine parked in the poller.
+func (fio fio) pollRead(data []byte) (int, error) {
+	pd := fio.f.poll()
+	fd := fio.f.fd
+	for {
+		// Register with the poller before retrying the syscall below; see
+		// the comment on (*pollDesc).register for why the order matters.
+		ready := pd.register(false)
+		n, err := syscall.Read(fd, data)
+		if n > 0 {
+			pd.remove(false, ready)
+			return n, nil
+		}
+		if err == nil {
+			pd.remove(false, ready)
+			return 0, io.EOF
+		}
+		if err == syscall.EINTR {
+			pd.remove(false, ready)
+			continue
+		}
+		if err != syscall.EAGAIN {
+			pd.remove(false, ready)
+			return 0, pollPathErr("read", fio.f.f, err)
+		}
+		if werr := pd.wait(fio.ctx, false, ready, fio.f.readDeadline.expired()); werr != nil {
+			return 0, pollPathErr("read", fio.f.f, werr)
+		}
+	}
+}
+
+func (fio fio) ReadAt(p []byte, off int64) (n int, err error) {
+	defer interrupt(fio.ctx)()
+	// TODO: oh dear O_NONBLOCK woes.
+	err = retryEINTR(fio.ctx, func() (err error) {
+		n, err = fio.f.f.ReadAt(p, off)
+		return err
+	})
+	return n, err
+}
+
+func (fio fio) Close() error {
+	if atomic.CompareAndSwapInt32(&fio.f.released, 0, 1) {
+		if fio.f.pollable {
+			pollDescForget(fio.f.fd)
+		}
+		releaseOpenSlot()
+	}
+	defer interrupt(fio.ctx)()
+	return retryEINTR(fio.ctx, fio.f.f.Close)
+}
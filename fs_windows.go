@@ -0,0 +1,368 @@
+// +build windows
+
+// Windows implementation: every File is opened with FILE_FLAG_OVERLAPPED
+// and associated with a single package-owned I/O completion port. Read and
+// Write issue an overlapped ReadFile/WriteFile and wait on the completion
+// port; canceling ctx calls CancelIoEx on the pending operation and the
+// result surfaces as context.Canceled. See fs_unix.go for the signal- and
+// poller-based implementation used there.
+package fs
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/net/context"
+)
+
+// File holds an open, overlapped-mode file handle.
+type File struct {
+	name string
+	h    syscall.Handle
+
+	// offset tracks the logical file position for sequential Read/Write,
+	// since overlapped I/O always specifies an explicit offset and
+	// ignores the (non-existent, for an overlapped handle) file pointer.
+	offset int64
+
+	// fdAccessed is set by Fd. From that point on Read and Write still
+	// use the completion port, but no longer race it against ctx.Done:
+	// the caller asked for the raw handle, so we assume they intend to
+	// drive it themselves and we stop promising cancellation.
+	fdAccessed int32
+
+	released int32
+
+	readDeadline  deadlineState
+	writeDeadline deadlineState
+}
+
+// IO returns an IO object bound to ctx for all of its operations.
+func (f *File) IO(ctx context.Context) IO {
+	return fio{f, ctx}
+}
+
+// Name returns the name of the file as presented to Open.
+func (f *File) Name() string { return f.name }
+
+// Fd returns the underlying Windows handle. Calling Fd disables
+// cancellation of future Read and Write calls on f: they keep using
+// overlapped I/O internally, but no longer abort on ctx.Done, mirroring
+// the tradeoff os.File.Fd makes on Unix.
+func (f *File) Fd() uintptr {
+	atomic.StoreInt32(&f.fdAccessed, 1)
+	return uintptr(f.h)
+}
+
+// SetNonBlocking is a no-op on Windows: every File already uses overlapped
+// (asynchronous) I/O, so there is no separate non-blocking mode to enable.
+func (f *File) SetNonBlocking() {}
+
+// SetDeadline sets the read and write deadlines for f, as SetReadDeadline
+// and SetWriteDeadline.
+func (f *File) SetDeadline(t time.Time) error {
+	f.readDeadline.set(t)
+	f.writeDeadline.set(t)
+	return nil
+}
+
+// SetReadDeadline sets the deadline for future Read calls on f's IO, as
+// well as any Read call currently blocked in the completion port. A zero
+// value for t disables the deadline. Once a deadline passes, Read returns
+// an *os.PathError wrapping os.ErrDeadlineExceeded, until SetReadDeadline
+// is called again.
+func (f *File) SetReadDeadline(t time.Time) error {
+	f.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline is like SetReadDeadline but for Write.
+func (f *File) SetWriteDeadline(t time.Time) error {
+	f.writeDeadline.set(t)
+	return nil
+}
+
+func newFile(name string, h syscall.Handle) *File {
+	ioCompletionPort().associate(h)
+	f := &File{name: name, h: h}
+	// There is no *os.File backing this handle to finalize for us, so we
+	// finalize the raw handle directly.
+	runtime.SetFinalizer(f, func(f *File) {
+		if atomic.CompareAndSwapInt32(&f.released, 0, 1) {
+			syscall.CloseHandle(f.h)
+			releaseOpenSlot()
+		}
+	})
+	return f
+}
+
+// Open opens the named file for reading.
+//
+// If the number of opened files exceeds OpenLimit, Open will block until
+// another file is closed.
+//
+// If there is an error, it will be of type *os.PathError.
+func Open(ctx context.Context, name string) (file *File, err error) {
+	return OpenFile(ctx, name, os.O_RDONLY, 0)
+}
+
+// OpenFile is the generalized open call; most users will use Open
+// or Create instead.
+//
+// If the number of open files exceeds OpenLimit, OpenFile will block until
+// another file is closed or ctx is done.
+//
+// If there is an error, it will be of type *os.PathError.
+func OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (file *File, err error) {
+	if err := acquireOpen(ctx); err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	h, err := createOverlapped(name, flag, perm)
+	if err != nil {
+		releaseOpenSlot()
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return newFile(name, h), nil
+}
+
+// Pipe returns a connected pair of Files; reads from r return bytes written to w.
+//
+// Each end of the pipe counts separately against OpenLimit.
+func Pipe(ctx context.Context) (r, w *File, err error) {
+	if err := acquireOpen(ctx); err != nil {
+		return nil, nil, err
+	}
+	if err := acquireOpen(ctx); err != nil {
+		releaseOpenSlot()
+		return nil, nil, err
+	}
+
+	var rh, wh syscall.Handle
+	if err := syscall.CreatePipe(&rh, &wh, nil, 0); err != nil {
+		releaseOpenSlot()
+		releaseOpenSlot()
+		return nil, nil, err
+	}
+	return newFile("|0", rh), newFile("|1", wh), nil
+}
+
+// createOverlapped translates the os.OpenFile flag/perm pair into the
+// CreateFile call needed to get an overlapped-capable handle.
+func createOverlapped(name string, flag int, perm os.FileMode) (syscall.Handle, error) {
+	var access uint32
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_RDONLY:
+		access = syscall.GENERIC_READ
+	case os.O_WRONLY:
+		access = syscall.GENERIC_WRITE
+	case os.O_RDWR:
+		access = syscall.GENERIC_READ | syscall.GENERIC_WRITE
+	}
+	if flag&os.O_APPEND != 0 {
+		access = access &^ syscall.GENERIC_WRITE
+		access |= syscall.FILE_APPEND_DATA
+	}
+
+	share := uint32(syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE | syscall.FILE_SHARE_DELETE)
+
+	var createMode uint32
+	switch {
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		createMode = syscall.CREATE_NEW
+	case flag&(os.O_CREATE|os.O_TRUNC) == os.O_CREATE|os.O_TRUNC:
+		createMode = syscall.CREATE_ALWAYS
+	case flag&os.O_CREATE == os.O_CREATE:
+		createMode = syscall.OPEN_ALWAYS
+	case flag&os.O_TRUNC == os.O_TRUNC:
+		createMode = syscall.TRUNCATE_EXISTING
+	default:
+		createMode = syscall.OPEN_EXISTING
+	}
+
+	namep, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	return syscall.CreateFile(namep, access, share, nil, createMode,
+		syscall.FILE_ATTRIBUTE_NORMAL|syscall.FILE_FLAG_OVERLAPPED, 0)
+}
+
+type fio struct {
+	f   *File
+	ctx context.Context
+}
+
+func (fio fio) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		atomic.StoreInt64(&fio.f.offset, offset)
+	case io.SeekCurrent:
+		atomic.AddInt64(&fio.f.offset, offset)
+	case io.SeekEnd:
+		var size int64
+		if err := syscall.GetFileSizeEx(fio.f.h, &size); err != nil {
+			return 0, &os.PathError{Op: "seek", Path: fio.f.name, Err: err}
+		}
+		atomic.StoreInt64(&fio.f.offset, size+offset)
+	}
+	return atomic.LoadInt64(&fio.f.offset), nil
+}
+
+func (fio fio) Read(p []byte) (int, error) {
+	n, err := fio.pio(p, atomic.LoadInt64(&fio.f.offset), false)
+	atomic.AddInt64(&fio.f.offset, int64(n))
+	return n, err
+}
+
+func (fio fio) Write(p []byte) (int, error) {
+	n, err := fio.pio(p, atomic.LoadInt64(&fio.f.offset), true)
+	atomic.AddInt64(&fio.f.offset, int64(n))
+	return n, err
+}
+
+func (fio fio) ReadAt(p []byte, off int64) (int, error) {
+	return fio.pio(p, off, false)
+}
+
+// pio issues one overlapped ReadFile/WriteFile and waits for it on the
+// completion port, cancelling it with CancelIoEx if ctx finishes first.
+func (fio fio) pio(p []byte, off int64, write bool) (int, error) {
+	op := newOverlappedOp(off)
+
+	var err error
+	if write {
+		err = syscall.WriteFile(fio.f.h, p, nil, &op.ov)
+	} else {
+		err = syscall.ReadFile(fio.f.h, p, nil, &op.ov)
+	}
+	if err != nil && err != syscall.ERROR_IO_PENDING {
+		if err == syscall.ERROR_HANDLE_EOF {
+			return 0, io.EOF
+		}
+		return 0, fio.pathErr(write, err)
+	}
+
+	if atomic.LoadInt32(&fio.f.fdAccessed) != 0 {
+		// The caller took the raw handle via Fd; honor the operation to
+		// completion but no longer race it against cancellation.
+		res := <-op.done
+		return int(res.n), fio.resultErr(write, res.err)
+	}
+
+	deadline := &fio.f.readDeadline
+	if write {
+		deadline = &fio.f.writeDeadline
+	}
+
+	select {
+	case res := <-op.done:
+		return int(res.n), fio.resultErr(write, res.err)
+	case <-fio.ctx.Done():
+		syscall.CancelIoEx(fio.f.h, &op.ov)
+		<-op.done // CancelIoEx only requests cancellation; wait for it to land.
+		return 0, fio.pathErr(write, context.Canceled)
+	case <-deadline.expired():
+		syscall.CancelIoEx(fio.f.h, &op.ov)
+		<-op.done // CancelIoEx only requests cancellation; wait for it to land.
+		return 0, fio.pathErr(write, os.ErrDeadlineExceeded)
+	}
+}
+
+func (fio fio) resultErr(write bool, err error) error {
+	if err == nil {
+		return nil
+	}
+	if err == syscall.ERROR_HANDLE_EOF || err == syscall.ERROR_BROKEN_PIPE {
+		return io.EOF
+	}
+	return fio.pathErr(write, err)
+}
+
+func (fio fio) pathErr(write bool, err error) error {
+	op := "read"
+	if write {
+		op = "write"
+	}
+	return &os.PathError{Op: op, Path: fio.f.name, Err: err}
+}
+
+func (fio fio) Close() error {
+	if atomic.CompareAndSwapInt32(&fio.f.released, 0, 1) {
+		syscall.CloseHandle(fio.f.h)
+		releaseOpenSlot()
+	}
+	return nil
+}
+
+// overlappedOp carries the syscall.Overlapped struct passed to
+// ReadFile/WriteFile together with the channel the completion port
+// delivers the result to. The OVERLAPPED pointer GetQueuedCompletionStatus
+// hands back is this struct's address, recovered with unsafe.Pointer.
+type overlappedOp struct {
+	ov   syscall.Overlapped
+	done chan ioResult
+}
+
+type ioResult struct {
+	n   uint32
+	err error
+}
+
+func newOverlappedOp(offset int64) *overlappedOp {
+	op := &overlappedOp{done: make(chan ioResult, 1)}
+	op.ov.Offset = uint32(offset)
+	op.ov.OffsetHigh = uint32(offset >> 32)
+	return op
+}
+
+// iocp is the package's single I/O completion port, shared by every File.
+var iocp struct {
+	once sync.Once
+	port syscall.Handle
+}
+
+// iocpHandle is a handle onto the package's single completion port,
+// returned by ioCompletionPort once it's guaranteed to be initialized.
+type iocpHandle struct{}
+
+func ioCompletionPort() iocpHandle {
+	iocp.once.Do(func() {
+		port, err := syscall.CreateIoCompletionPort(syscall.InvalidHandle, 0, 0, 0)
+		if err != nil {
+			panic("fs: CreateIoCompletionPort: " + err.Error())
+		}
+		iocp.port = port
+		go iocpRun()
+	})
+	return iocpHandle{}
+}
+
+func (iocpHandle) associate(h syscall.Handle) {
+	if _, err := syscall.CreateIoCompletionPort(h, iocp.port, 0, 0); err != nil {
+		panic("fs: CreateIoCompletionPort(associate): " + err.Error())
+	}
+}
+
+// iocpRun is the package's single completion-port goroutine. It
+// demultiplexes completed overlapped operations to the overlappedOp that
+// issued them.
+func iocpRun() {
+	for {
+		var n uint32
+		var key uintptr
+		var ov *syscall.Overlapped
+		err := syscall.GetQueuedCompletionStatus(iocp.port, &n, &key, &ov, syscall.INFINITE)
+		if ov == nil {
+			continue
+		}
+		op := (*overlappedOp)(unsafe.Pointer(ov))
+		op.done <- ioResult{n: n, err: err}
+	}
+}
@@ -0,0 +1,113 @@
+// +build !windows
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/net/context"
+)
+
+// Stat returns a FileInfo describing the named file.
+//
+// If there is an error, it will be of type *os.PathError.
+func Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	defer interrupt(ctx)()
+	var fi os.FileInfo
+	err := retryEINTR(ctx, func() (err error) {
+		fi, err = os.Stat(name)
+		return err
+	})
+	return fi, err
+}
+
+// Lstat is like Stat but, if name names a symbolic link, describes the
+// link rather than the file it points to.
+//
+// If there is an error, it will be of type *os.PathError.
+func Lstat(ctx context.Context, name string) (os.FileInfo, error) {
+	defer interrupt(ctx)()
+	var fi os.FileInfo
+	err := retryEINTR(ctx, func() (err error) {
+		fi, err = os.Lstat(name)
+		return err
+	})
+	return fi, err
+}
+
+// Mkdir creates a new directory with the given name and permission bits
+// (before umask).
+//
+// If there is an error, it will be of type *os.PathError.
+func Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	defer interrupt(ctx)()
+	return retryEINTR(ctx, func() error { return os.Mkdir(name, perm) })
+}
+
+// MkdirAll creates a directory named path, along with any necessary
+// parents, and returns nil, or else returns an error. The permission bits
+// perm (before umask) are used for all directories that MkdirAll creates.
+func MkdirAll(ctx context.Context, path string, perm os.FileMode) error {
+	defer interrupt(ctx)()
+	return os.MkdirAll(path, perm)
+}
+
+// Remove removes the named file or (empty) directory.
+//
+// If there is an error, it will be of type *os.PathError.
+func Remove(ctx context.Context, name string) error {
+	defer interrupt(ctx)()
+	return retryEINTR(ctx, func() error { return os.Remove(name) })
+}
+
+// RemoveAll removes path and any children it contains. It removes
+// everything it can but returns the first error it encounters.
+func RemoveAll(ctx context.Context, path string) error {
+	defer interrupt(ctx)()
+	return os.RemoveAll(path)
+}
+
+// Rename renames (moves) oldpath to newpath.
+//
+// If there is an error, it will be of type *os.LinkError.
+func Rename(ctx context.Context, oldpath, newpath string) error {
+	defer interrupt(ctx)()
+	return retryEINTR(ctx, func() error { return os.Rename(oldpath, newpath) })
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+//
+// If there is an error, it will be of type *os.LinkError.
+func Symlink(ctx context.Context, oldname, newname string) error {
+	defer interrupt(ctx)()
+	return retryEINTR(ctx, func() error { return os.Symlink(oldname, newname) })
+}
+
+// Readlink returns the destination of the named symbolic link.
+//
+// If there is an error, it will be of type *os.PathError.
+func Readlink(ctx context.Context, name string) (string, error) {
+	defer interrupt(ctx)()
+	var s string
+	err := retryEINTR(ctx, func() (err error) {
+		s, err = os.Readlink(name)
+		return err
+	})
+	return s, err
+}
+
+// Chmod changes the mode of the named file to mode.
+//
+// If there is an error, it will be of type *os.PathError.
+func Chmod(ctx context.Context, name string, mode os.FileMode) error {
+	defer interrupt(ctx)()
+	return retryEINTR(ctx, func() error { return os.Chmod(name, mode) })
+}
+
+// Chown changes the numeric uid and gid of the named file.
+//
+// If there is an error, it will be of type *os.PathError.
+func Chown(ctx context.Context, name string, uid, gid int) error {
+	defer interrupt(ctx)()
+	return retryEINTR(ctx, func() error { return os.Chown(name, uid, gid) })
+}
@@ -0,0 +1,62 @@
+// +build windows
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/net/context"
+)
+
+// Stat, Lstat, Mkdir, and the rest below have no cheap per-syscall
+// interrupt mechanism on Windows the way SIGUSR1 gives Unix one; ctx is
+// accepted for API symmetry with fs_unix.go but metadata calls are not
+// yet cancellable here. The I/O completion port used by File's Read and
+// Write doesn't apply to these: they aren't overlapped-capable handles.
+
+// Stat returns a FileInfo describing the named file.
+func Stat(ctx context.Context, name string) (os.FileInfo, error) { return os.Stat(name) }
+
+// Lstat is like Stat but, if name names a symbolic link, describes the
+// link rather than the file it points to.
+func Lstat(ctx context.Context, name string) (os.FileInfo, error) { return os.Lstat(name) }
+
+// Mkdir creates a new directory with the given name and permission bits.
+func Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+// MkdirAll creates a directory named path, along with any necessary
+// parents.
+func MkdirAll(ctx context.Context, path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+// Remove removes the named file or (empty) directory.
+func Remove(ctx context.Context, name string) error { return os.Remove(name) }
+
+// RemoveAll removes path and any children it contains.
+func RemoveAll(ctx context.Context, path string) error { return os.RemoveAll(path) }
+
+// Rename renames (moves) oldpath to newpath.
+func Rename(ctx context.Context, oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+func Symlink(ctx context.Context, oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+// Readlink returns the destination of the named symbolic link.
+func Readlink(ctx context.Context, name string) (string, error) { return os.Readlink(name) }
+
+// Chmod changes the mode of the named file to mode.
+func Chmod(ctx context.Context, name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+// Chown changes the numeric uid and gid of the named file.
+func Chown(ctx context.Context, name string, uid, gid int) error {
+	return os.Chown(name, uid, gid)
+}
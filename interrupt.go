@@ -1,3 +1,5 @@
+// +build !windows
+
 package fs
 
 import (
@@ -19,7 +21,9 @@ func funcPC(f interface{}) uintptr {
 	return **(**uintptr)(unsafe.Pointer(pc))
 }
 
-func threadID() uintptr
+// threadID is declared per-OS (interrupt_linux.go has a native Go
+// implementation; interrupt_darwin.go has its own assembly-backed stub),
+// not here, so neither redeclares the other.
 func sigtramp()
 
 var intrHandler = func(sig int32) {}
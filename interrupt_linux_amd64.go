@@ -0,0 +1,12 @@
+package fs
+
+// sigactiont mirrors the kernel's struct sigaction layout on linux/amd64
+// (handler, flags, restorer, mask — matching runtime's own
+// defs_linux_amd64.go), which is what the raw rt_sigaction syscall in
+// interrupt_linux.go expects.
+type sigactiont struct {
+	sa_handler  uintptr
+	sa_flags    uint64
+	sa_restorer uintptr
+	sa_mask     uint64
+}
@@ -0,0 +1,126 @@
+package fs
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// OpenLimit is the maximum number of file descriptors that can be open
+// simultaneously by the fs package.
+//
+// Initial value is 10% less than RLIMIT_NOFILE at process initialization
+// (Unix) or a fixed default (Windows, which has no equivalent rlimit).
+var OpenLimit int
+
+// openSem is a counting semaphore enforcing OpenLimit. It is implemented
+// as a buffered channel of empty structs: acquiring takes a slot out,
+// releasing puts one back. A channel (rather than a plain counter) lets
+// acquire select on ctx.Done() instead of spinning.
+var openSem struct {
+	mu sync.Mutex
+	c  chan struct{}
+
+	// resize is closed and replaced every time SetOpenLimit swaps in a
+	// new c, so a goroutine already blocked in acquireOpen on the old
+	// (now-abandoned) channel notices and waits on the new one instead,
+	// rather than blocking forever on a channel no release will ever
+	// reach again.
+	resize chan struct{}
+}
+
+func init() {
+	SetOpenLimit(initialOpenLimit())
+}
+
+// SetOpenLimit resizes the semaphore backing OpenLimit to n slots.
+//
+// Shrinking the limit does not close any files already open above the new
+// limit; it only throttles future acquires until enough have been released
+// to come back under n.
+func SetOpenLimit(n int) {
+	openSem.mu.Lock()
+	defer openSem.mu.Unlock()
+
+	inUse := OpenLimit - len(openSem.c) // 0 on the very first call, openSem.c == nil
+	avail := n - inUse
+	if avail < 0 {
+		avail = 0
+	}
+
+	c := make(chan struct{}, n)
+	for i := 0; i < avail; i++ {
+		c <- struct{}{}
+	}
+	openSem.c = c
+	OpenLimit = n
+
+	if openSem.resize != nil {
+		close(openSem.resize)
+	}
+	openSem.resize = make(chan struct{})
+}
+
+// acquireOpen blocks until a slot under OpenLimit is available or ctx is
+// done, whichever comes first. If SetOpenLimit swaps in a new semaphore
+// channel while this call is waiting on the old one, it notices via resize
+// and waits on the new channel instead, rather than blocking forever on a
+// channel no release will ever reach again.
+func acquireOpen(ctx context.Context) error {
+	for {
+		openSem.mu.Lock()
+		c := openSem.c
+		resize := openSem.resize
+		openSem.mu.Unlock()
+
+		select {
+		case <-c:
+			return nil
+		default:
+		}
+
+		select {
+		case <-c:
+			return nil
+		case <-resize:
+		case <-ctx.Done():
+			return context.Canceled
+		}
+	}
+}
+
+// releaseOpenSlot unconditionally returns one slot to the semaphore. Use it
+// for acquires that never made it into a *File (e.g. OpenFile failed after
+// acquireOpen succeeded); for a File's own teardown, gate the call on a CAS
+// over its released field instead (see fs_unix.go/fs_windows.go), since
+// Close and the finalizer both need to share that same gate with whatever
+// else they tear down (e.g. pollDescForget on Unix).
+func releaseOpenSlot() {
+	openSem.mu.Lock()
+	c := openSem.c
+	openSem.mu.Unlock()
+
+	select {
+	case c <- struct{}{}:
+	default:
+		// The semaphore was shrunk by SetOpenLimit after this slot was
+		// acquired from a larger one; dropping the token here is correct,
+		// it simply means the limit is still draining down to n.
+	}
+}
+
+// OpenStats reports current pressure on OpenLimit.
+type OpenStats struct {
+	Limit int // current value of OpenLimit
+	InUse int // number of slots currently acquired
+}
+
+// Stats returns the current OpenLimit and how many of its slots are
+// currently in use.
+func Stats() OpenStats {
+	openSem.mu.Lock()
+	c := openSem.c
+	limit := OpenLimit
+	openSem.mu.Unlock()
+	return OpenStats{Limit: limit, InUse: limit - len(c)}
+}
@@ -0,0 +1,22 @@
+// +build !windows
+
+package fs
+
+import "syscall"
+
+// fallbackOpenLimit is used when RLIMIT_NOFILE can't be read, or is
+// RLIM_INFINITY (an unbounded limit can't be scaled down to 90% of
+// anything meaningful).
+const fallbackOpenLimit = 256
+
+// initialOpenLimit returns 90% of RLIMIT_NOFILE.
+func initialOpenLimit() int {
+	var rlim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim); err != nil {
+		return fallbackOpenLimit
+	}
+	if int64(rlim.Cur) == syscall.RLIM_INFINITY {
+		return fallbackOpenLimit
+	}
+	return int(rlim.Cur) * 9 / 10
+}
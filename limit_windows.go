@@ -0,0 +1,9 @@
+// +build windows
+
+package fs
+
+// initialOpenLimit returns a fixed default: Windows has no RLIMIT_NOFILE
+// equivalent to size OpenLimit from.
+func initialOpenLimit() int {
+	return 256
+}
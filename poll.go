@@ -0,0 +1,184 @@
+// +build !windows
+
+package fs
+
+import (
+	"os"
+	"sync"
+	"syscall"
+
+	"golang.org/x/net/context"
+)
+
+// pollDesc tracks the goroutines waiting for read or write readiness on a
+// single file descriptor registered with the package poller. Unlike the
+// signal-based interrupt path, waking a pollDesc costs no syscalls and
+// supports any number of concurrent waiters on the same fd.
+type pollDesc struct {
+	fd int
+
+	mu      sync.Mutex
+	readers []chan struct{}
+	writers []chan struct{}
+}
+
+// register adds a new waiter channel to the direction requested (write, if
+// true, otherwise read) and returns it. Callers must register *before*
+// re-attempting their syscall, not after seeing EAGAIN: registering only
+// once already certain they'll block would leave a window, between the
+// syscall and the registration, in which an edge-triggered readiness event
+// can arrive and be delivered to no one (wake finds the waiter list empty).
+// Since an edge-triggered fd only fires again on a not-ready-to-ready
+// transition, a wakeup lost in that window is lost forever. Registering
+// first closes the window: any edge arriving from that point on, including
+// one for data that was already sitting there, is queued on the channel
+// before wait ever blocks on it.
+func (pd *pollDesc) register(write bool) chan struct{} {
+	ready := make(chan struct{}, 1)
+	pd.mu.Lock()
+	if write {
+		pd.writers = append(pd.writers, ready)
+	} else {
+		pd.readers = append(pd.readers, ready)
+	}
+	pd.mu.Unlock()
+	return ready
+}
+
+// remove takes ready back out of the waiter list for the given direction.
+// Call it once a registered waiter resolves readiness itself (by retrying
+// its syscall) instead of going on to call wait.
+func (pd *pollDesc) remove(write bool, ready chan struct{}) {
+	pd.mu.Lock()
+	pd.removeLocked(write, ready)
+	pd.mu.Unlock()
+}
+
+// wait parks the calling goroutine until ready fires, ctx is done, or
+// expired is closed by an armed deadline. ready must have come from
+// register on this same pollDesc and direction. It is safe to call wait on
+// the same pollDesc from multiple goroutines simultaneously.
+func (pd *pollDesc) wait(ctx context.Context, write bool, ready chan struct{}, expired <-chan struct{}) error {
+	select {
+	case <-ready:
+		return nil
+	case <-expired:
+		pd.remove(write, ready)
+		return os.ErrDeadlineExceeded
+	case <-ctx.Done():
+		pd.remove(write, ready)
+		return context.Canceled
+	}
+}
+
+func (pd *pollDesc) removeLocked(write bool, ready chan struct{}) {
+	list := &pd.readers
+	if write {
+		list = &pd.writers
+	}
+	for i, c := range *list {
+		if c == ready {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return
+		}
+	}
+}
+
+// wake notifies every goroutine currently waiting on the given direction.
+// They will race to re-attempt their syscall; losers see EAGAIN again and
+// simply re-register.
+func (pd *pollDesc) wake(write bool) {
+	pd.mu.Lock()
+	list := &pd.readers
+	if write {
+		list = &pd.writers
+	}
+	woken := *list
+	*list = nil
+	pd.mu.Unlock()
+
+	for _, c := range woken {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// poller is the single package-owned instance demultiplexing readiness
+// events for every registered pollDesc. It is started lazily so that
+// programs which never touch a pollable fd pay no cost.
+var poller struct {
+	once sync.Once
+
+	mu    sync.Mutex
+	descs map[int]*pollDesc
+}
+
+func pollerInit() {
+	poller.descs = make(map[int]*pollDesc)
+	pollerOpen()
+	go pollerRun()
+}
+
+// pollDescFor returns the pollDesc for fd, registering it with the poller
+// the first time it is seen.
+func pollDescFor(fd int) *pollDesc {
+	poller.once.Do(pollerInit)
+
+	poller.mu.Lock()
+	defer poller.mu.Unlock()
+	if pd, ok := poller.descs[fd]; ok {
+		return pd
+	}
+	pd := &pollDesc{fd: fd}
+	poller.descs[fd] = pd
+	pollerAdd(fd)
+	return pd
+}
+
+// pollDescForget removes fd's bookkeeping once the File owning it is
+// closed. It is not an error to forget an fd that was never registered.
+func pollDescForget(fd int) {
+	poller.mu.Lock()
+	pd, ok := poller.descs[fd]
+	if ok {
+		delete(poller.descs, fd)
+	}
+	poller.mu.Unlock()
+	if ok {
+		pollerDel(fd)
+		pd.wake(false)
+		pd.wake(true)
+	}
+}
+
+// isPollable reports whether fd refers to a pipe, FIFO, socket, or tty:
+// descriptors for which the kernel supports edge-triggered readiness
+// notification and O_NONBLOCK semantics. Regular files report ready
+// immediately from epoll/kqueue, which doesn't help us, so they keep using
+// the signal-based interrupt path.
+func isPollable(fd uintptr) bool {
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(fd), &st); err != nil {
+		return false
+	}
+	switch st.Mode & syscall.S_IFMT {
+	case syscall.S_IFIFO, syscall.S_IFSOCK:
+		return true
+	case syscall.S_IFCHR:
+		return isatty(fd)
+	default:
+		return false
+	}
+}
+
+// pollPathErr wraps err, if non-nil, as an *os.PathError for op on f,
+// matching the shape returned by the os package and the rest of this
+// package's IO methods.
+func pollPathErr(op string, f *os.File, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &os.PathError{Op: op, Path: f.Name(), Err: err}
+}
@@ -0,0 +1,77 @@
+// +build darwin
+
+package fs
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var kqfd = -1
+
+func pollerOpen() {
+	fd, err := syscall.Kqueue()
+	if err != nil {
+		panic("fs: kqueue: " + err.Error())
+	}
+	kqfd = fd
+}
+
+func pollerAdd(fd int) {
+	changes := []syscall.Kevent_t{
+		{Ident: uint64(fd), Filter: syscall.EVFILT_READ, Flags: syscall.EV_ADD | syscall.EV_CLEAR},
+		{Ident: uint64(fd), Filter: syscall.EVFILT_WRITE, Flags: syscall.EV_ADD | syscall.EV_CLEAR},
+	}
+	if _, err := syscall.Kevent(kqfd, changes, nil, nil); err != nil {
+		panic("fs: kevent(EV_ADD): " + err.Error())
+	}
+}
+
+func pollerDel(fd int) {
+	changes := []syscall.Kevent_t{
+		{Ident: uint64(fd), Filter: syscall.EVFILT_READ, Flags: syscall.EV_DELETE},
+		{Ident: uint64(fd), Filter: syscall.EVFILT_WRITE, Flags: syscall.EV_DELETE},
+	}
+	syscall.Kevent(kqfd, changes, nil, nil)
+}
+
+// pollerRun is the package's single poller goroutine. It blocks in kevent
+// and demultiplexes readiness events to the per-fd pollDesc registered in
+// pollDescFor.
+func pollerRun() {
+	events := make([]syscall.Kevent_t, 128)
+	for {
+		n, err := syscall.Kevent(kqfd, nil, events, nil)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			panic("fs: kevent(wait): " + err.Error())
+		}
+		for i := 0; i < n; i++ {
+			ev := events[i]
+			poller.mu.Lock()
+			pd := poller.descs[int(ev.Ident)]
+			poller.mu.Unlock()
+			if pd == nil {
+				continue
+			}
+			switch ev.Filter {
+			case syscall.EVFILT_READ:
+				pd.wake(false)
+			case syscall.EVFILT_WRITE:
+				pd.wake(true)
+			}
+		}
+	}
+}
+
+const _TIOCGETA = 0x40487413
+
+// isatty reports whether fd is a terminal, using the same ioctl the os
+// package's isatty helpers are built on.
+func isatty(fd uintptr) bool {
+	var termios [256]byte // sizeof(struct termios) with padding for safety
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, _TIOCGETA, uintptr(unsafe.Pointer(&termios[0])))
+	return errno == 0
+}
@@ -0,0 +1,78 @@
+package fs
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var epfd = -1
+
+func pollerOpen() {
+	fd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		panic("fs: epoll_create1: " + err.Error())
+	}
+	epfd = fd
+}
+
+func pollerAdd(fd int) {
+	// syscall.EPOLLET is defined as a negative int constant (its top bit
+	// set), so it can't be OR'd into the uint32 field as a constant
+	// expression without overflowing; route it through a variable so the
+	// conversion happens at runtime instead.
+	events := syscall.EPOLLIN | syscall.EPOLLOUT | syscall.EPOLLRDHUP | syscall.EPOLLET
+	ev := syscall.EpollEvent{
+		Events: uint32(events),
+		Fd:     int32(fd),
+	}
+	if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+		panic("fs: epoll_ctl(ADD): " + err.Error())
+	}
+}
+
+func pollerDel(fd int) {
+	// Linux ignores the event argument for EPOLL_CTL_DEL, but older
+	// kernels (pre-2.6.9) require a non-nil pointer.
+	syscall.EpollCtl(epfd, syscall.EPOLL_CTL_DEL, fd, &syscall.EpollEvent{})
+}
+
+// pollerRun is the package's single poller goroutine. It blocks in
+// epoll_wait and demultiplexes readiness events to the per-fd pollDesc
+// registered in pollDescFor.
+func pollerRun() {
+	events := make([]syscall.EpollEvent, 128)
+	for {
+		n, err := syscall.EpollWait(epfd, events, -1)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			panic("fs: epoll_wait: " + err.Error())
+		}
+		for i := 0; i < n; i++ {
+			ev := events[i]
+			poller.mu.Lock()
+			pd := poller.descs[int(ev.Fd)]
+			poller.mu.Unlock()
+			if pd == nil {
+				continue
+			}
+			if ev.Events&(syscall.EPOLLIN|syscall.EPOLLHUP|syscall.EPOLLERR|syscall.EPOLLRDHUP) != 0 {
+				pd.wake(false)
+			}
+			if ev.Events&(syscall.EPOLLOUT|syscall.EPOLLHUP|syscall.EPOLLERR) != 0 {
+				pd.wake(true)
+			}
+		}
+	}
+}
+
+const _TCGETS = 0x5401
+
+// isatty reports whether fd is a terminal, using the same ioctl the os
+// package's isatty helpers are built on.
+func isatty(fd uintptr) bool {
+	var termios [64]byte // sizeof(struct termios) on linux/amd64 is smaller; over-allocate for other arches
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, _TCGETS, uintptr(unsafe.Pointer(&termios[0])))
+	return errno == 0
+}